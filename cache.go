@@ -0,0 +1,91 @@
+package main
+
+// matchResult is the cached outcome of evaluating a path against a
+// GitIgnore. fired records whether any of the GitIgnore's rules
+// actually matched path, as opposed to matched defaulting to false
+// because none did - a distinction a GitIgnoreStack needs to decide
+// whether a layer's result should override an earlier layer's, or
+// whether the earlier layer's result should simply carry through.
+type matchResult struct {
+	matched       bool
+	fired         bool
+	childMayMatch bool
+}
+
+// CacheStats reports how many GitIgnore match lookups were served
+// from its cache versus recomputed.
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// matchCacheKey identifies a cached lookup. isDir is part of the
+// key, not just path, because IsDir rules only apply when isDir is
+// true, so the same path can legitimately have two different
+// results depending on it.
+type matchCacheKey struct {
+	path  string
+	isDir bool
+}
+
+// matchCache is a fixed-size cache mapping a (path, isDir) lookup
+// to its previously computed match result, evicting the oldest
+// entry once full. A nil *matchCache is valid and simply disables
+// caching.
+type matchCache struct {
+	size    int
+	entries map[matchCacheKey]matchResult
+	order   []matchCacheKey
+	hits    int
+	misses  int
+}
+
+// newMatchCache returns a matchCache holding at most size entries,
+// or nil if size is zero or negative, which disables caching.
+func newMatchCache(size int) *matchCache {
+	if size <= 0 {
+		return nil
+	}
+	return &matchCache{
+		size:    size,
+		entries: make(map[matchCacheKey]matchResult, size),
+	}
+}
+
+func (c *matchCache) get(path string, isDir bool) (matchResult, bool) {
+	if c == nil {
+		return matchResult{}, false
+	}
+
+	r, ok := c.entries[matchCacheKey{path: path, isDir: isDir}]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return r, ok
+}
+
+func (c *matchCache) put(path string, isDir bool, r matchResult) {
+	if c == nil {
+		return
+	}
+
+	key := matchCacheKey{path: path, isDir: isDir}
+	if _, ok := c.entries[key]; !ok {
+		if len(c.order) >= c.size {
+			oldest := c.order[0]
+			c.order = c.order[1:]
+			delete(c.entries, oldest)
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = r
+}
+
+func (c *matchCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: c.hits, Misses: c.misses}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// GitIgnore is an ordered collection of parsed .gitignore rules.
+// Rules are evaluated in the order they were defined, so a later
+// negated rule (`!pattern`) can un-ignore a path matched by an
+// earlier rule, mirroring real gitignore semantics.
+type GitIgnore struct {
+	rules []*Rule
+	cache *matchCache
+}
+
+// CompileIgnoreLines compiles the given raw .gitignore lines, in
+// order, into a GitIgnore using the default CompileOptions.
+func CompileIgnoreLines(lines ...string) *GitIgnore {
+	return CompileIgnoreLinesWithOptions(CompileOptions{}, lines...)
+}
+
+// CompileIgnoreLinesWithOptions compiles the given raw .gitignore
+// lines, in order, into a GitIgnore using opts.
+func CompileIgnoreLinesWithOptions(opts CompileOptions, lines ...string) *GitIgnore {
+	g := &GitIgnore{cache: newMatchCache(opts.Cache)}
+
+	for _, line := range lines {
+		rule := parseLine(line, opts)
+		if rule == nil {
+			continue
+		}
+		g.rules = append(g.rules, rule)
+	}
+
+	return g
+}
+
+// CompileIgnoreReader reads .gitignore rules line by line from r
+// and compiles them into a GitIgnore using the default
+// CompileOptions.
+func CompileIgnoreReader(r io.Reader) (*GitIgnore, error) {
+	return CompileIgnoreReaderWithOptions(CompileOptions{}, r)
+}
+
+// CompileIgnoreReaderWithOptions reads .gitignore rules line by
+// line from r and compiles them into a GitIgnore using opts.
+func CompileIgnoreReaderWithOptions(opts CompileOptions, r io.Reader) (*GitIgnore, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return CompileIgnoreLinesWithOptions(opts, lines...), nil
+}
+
+// CompileIgnoreFile reads the .gitignore file at path and compiles
+// its rules into a GitIgnore using the default CompileOptions.
+func CompileIgnoreFile(path string) (*GitIgnore, error) {
+	return CompileIgnoreFileWithOptions(CompileOptions{}, path)
+}
+
+// CompileIgnoreFileWithOptions reads the .gitignore file at path
+// and compiles its rules into a GitIgnore using opts.
+func CompileIgnoreFileWithOptions(opts CompileOptions, path string) (*GitIgnore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return CompileIgnoreReaderWithOptions(opts, f)
+}
+
+// parseLine applies the comment, blank-line, and trailing-space
+// trimming rules of the gitignore format to a single raw line
+// before handing it to parseWithOptions. It returns nil for lines
+// that produce no rule. A leading `\#` or `\!` is left untouched
+// here: the parser's backslash-escape handling turns it into a
+// literal `#` or `!` without making the line look like a comment
+// or negation.
+func parseLine(line string, opts CompileOptions) *Rule {
+	if line == "" {
+		return nil
+	}
+
+	trimmed := trimTrailingSpace(line)
+	if trimmed == "" {
+		return nil
+	}
+
+	if trimmed[0] == '#' {
+		return nil
+	}
+
+	return parseWithOptions(trimmed, opts)
+}
+
+// trimTrailingSpace strips trailing spaces from line, unless they
+// are escaped with a preceding backslash, in which case a single
+// literal trailing space is kept and trimming continues on the
+// part of line before it.
+func trimTrailingSpace(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == CHAR_SPACE {
+		if end >= 2 && line[end-2] == '\\' {
+			return trimTrailingSpace(line[:end-2]) + string(CHAR_SPACE)
+		}
+		end--
+	}
+	return line[:end]
+}
+
+// Match reports whether path is ignored by g, and whether any
+// descendant of path could still match one of g's non-negated
+// rules. A
+// directory walker can use the second value to prune a subtree
+// once matched is true and childMayMatch is false.
+func (g *GitIgnore) Match(path string) (matched, childMayMatch bool) {
+	return g.MatchIsDir(path, false)
+}
+
+// MatchIsDir reports whether path is ignored by g, evaluating
+// every rule in order so that a later negated rule can un-ignore a
+// match from an earlier one. isDir indicates whether path itself
+// is a directory, since directory-only rules (a trailing `/` in
+// the pattern) only apply to directories. The second return value
+// reports whether any descendant of path could still match one of
+// g's non-negated rules. Results are served from g's match cache
+// when one was configured via CompileOptions.Cache.
+func (g *GitIgnore) MatchIsDir(path string, isDir bool) (matched, childMayMatch bool) {
+	matched, _, childMayMatch = g.matchIsDirFrom(path, isDir, false)
+	return matched, childMayMatch
+}
+
+// matchIsDirFrom is MatchIsDir's cached evaluation, seeded with the
+// matched state carried in from an earlier, less specific layer.
+// When none of g's rules fire for path, fired is false and matched
+// is returned unchanged from seed, letting a GitIgnoreStack treat a
+// layer with no opinion on path as transparent rather than
+// resetting the decision to "not ignored". The underlying (fired,
+// matched-if-fired) fact is what's cached, since it doesn't depend
+// on seed and so is equally valid for any caller's seed.
+func (g *GitIgnore) matchIsDirFrom(path string, isDir bool, seed bool) (matched, fired, childMayMatch bool) {
+	if r, ok := g.cache.get(path, isDir); ok {
+		if !r.fired {
+			return seed, false, r.childMayMatch
+		}
+		return r.matched, true, r.childMayMatch
+	}
+
+	for _, rule := range g.rules {
+		if rule.IsDir && !isDir {
+			continue
+		}
+		if rule.Matcher(path) {
+			matched = !rule.IsNegate
+			fired = true
+		}
+		if !rule.IsNegate && rule.ChildMayMatch(path) {
+			childMayMatch = true
+		}
+	}
+
+	g.cache.put(path, isDir, matchResult{matched: matched, fired: fired, childMayMatch: childMayMatch})
+	if !fired {
+		return seed, false, childMayMatch
+	}
+	return matched, true, childMayMatch
+}
+
+// Stats reports how many of g's Match/MatchIsDir lookups were
+// served from its cache versus recomputed.
+func (g *GitIgnore) Stats() CacheStats {
+	return g.cache.stats()
+}
+
+// GitIgnoreStack layers multiple GitIgnore instances, such as a
+// repo-root .gitignore together with the per-directory ones found
+// while walking a tree, and evaluates them from least to most
+// specific.
+type GitIgnoreStack struct {
+	layers []*GitIgnore
+}
+
+// Push adds g as the next, more specific layer in the stack.
+func (s *GitIgnoreStack) Push(g *GitIgnore) {
+	s.layers = append(s.layers, g)
+}
+
+// Match reports whether path is ignored once every layer in the
+// stack has been applied in push order, and whether any descendant
+// of path could still match a non-negated rule in any layer.
+func (s *GitIgnoreStack) Match(path string) (matched, childMayMatch bool) {
+	return s.MatchIsDir(path, false)
+}
+
+// MatchIsDir reports whether path is ignored once every layer in
+// the stack has been applied, evaluating every layer in push order
+// against its own match cache. A layer whose rules don't mention
+// path leaves the prior layer's decision untouched; a layer that does fire overrides it,
+// so a later, more specific layer can still un-ignore a match from
+// an earlier one, exactly as a later rule within a single GitIgnore
+// would.
+func (s *GitIgnoreStack) MatchIsDir(path string, isDir bool) (matched, childMayMatch bool) {
+	for _, g := range s.layers {
+		var c bool
+		matched, _, c = g.matchIsDirFrom(path, isDir, matched)
+		if c {
+			childMayMatch = true
+		}
+	}
+
+	return matched, childMayMatch
+}
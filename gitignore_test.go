@@ -0,0 +1,160 @@
+package main
+
+import "testing"
+
+func TestGitIgnoreMatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		path  string
+		want  bool
+	}{
+		{
+			name:  "simple ignore",
+			lines: []string{"*.log"},
+			path:  "debug.log",
+			want:  true,
+		},
+		{
+			name:  "blank lines and comments are skipped",
+			lines: []string{"", "# comment", "*.log"},
+			path:  "app.log",
+			want:  true,
+		},
+		{
+			name:  "later negation un-ignores an earlier match",
+			lines: []string{"*.log", "!important.log"},
+			path:  "important.log",
+			want:  false,
+		},
+		{
+			name:  "negation only applies to later matches",
+			lines: []string{"!important.log", "*.log"},
+			path:  "important.log",
+			want:  true,
+		},
+		{
+			name:  "escaped leading hash is a literal pattern",
+			lines: []string{`\#keep`},
+			path:  "#keep",
+			want:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := CompileIgnoreLines(tc.lines...)
+			if got, _ := g.Match(tc.path); got != tc.want {
+				t.Errorf("Match(%q) = %v, want %v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGitIgnoreStack(t *testing.T) {
+	root := CompileIgnoreLines("*.log")
+	sub := CompileIgnoreLines("!important.log")
+
+	var s GitIgnoreStack
+	s.Push(root)
+	s.Push(sub)
+
+	if matched, _ := s.Match("debug.log"); matched != true {
+		t.Errorf("Match(debug.log) = false, want true")
+	}
+	if matched, _ := s.Match("important.log"); matched != false {
+		t.Errorf("Match(important.log) = true, want false")
+	}
+}
+
+func TestGitIgnoreStackUsesLayerCache(t *testing.T) {
+	root := CompileIgnoreLinesWithOptions(CompileOptions{Cache: 2}, "*.log")
+	sub := CompileIgnoreLinesWithOptions(CompileOptions{Cache: 2}, "!important.log")
+
+	var s GitIgnoreStack
+	s.Push(root)
+	s.Push(sub)
+
+	s.Match("debug.log")
+	s.Match("debug.log")
+
+	if stats := root.Stats(); stats.Hits != 1 {
+		t.Errorf("root layer Hits = %d, want 1", stats.Hits)
+	}
+	if stats := sub.Stats(); stats.Hits != 1 {
+		t.Errorf("sub layer Hits = %d, want 1", stats.Hits)
+	}
+}
+
+func TestGitIgnoreChildMayMatch(t *testing.T) {
+	cases := []struct {
+		name string
+		rule string
+		path string
+		want bool
+	}{
+		{name: "rooted pattern, unrelated path prunes", rule: "/build", path: "src", want: false},
+		{name: "rooted pattern, shared prefix descends", rule: "/build", path: "build", want: true},
+		{name: "double star always descends", rule: "vendor/**/testdata", path: "vendor", want: true},
+		{name: "unanchored pattern always descends", rule: "*.log", path: "src", want: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := CompileIgnoreLines(tc.rule)
+			if _, childMayMatch := g.MatchIsDir(tc.path, true); childMayMatch != tc.want {
+				t.Errorf("childMayMatch(%q) = %v, want %v", tc.path, childMayMatch, tc.want)
+			}
+		})
+	}
+}
+
+func TestGitIgnoreCaseInsensitive(t *testing.T) {
+	g := CompileIgnoreLinesWithOptions(CompileOptions{CaseInsensitive: true}, "*.LOG")
+
+	if matched, _ := g.Match("debug.log"); !matched {
+		t.Errorf("Match(debug.log) = false, want true")
+	}
+
+	sensitive := CompileIgnoreLines("*.LOG")
+	if matched, _ := sensitive.Match("debug.log"); matched {
+		t.Errorf("case-sensitive Match(debug.log) = true, want false")
+	}
+}
+
+func TestTrimTrailingSpace(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "no trailing space", line: "foo", want: "foo"},
+		{name: "plain trailing spaces are stripped", line: "foo   ", want: "foo"},
+		{name: "single escaped trailing space is kept", line: `foo\ `, want: "foo "},
+		{name: "plain spaces before an escaped trailing space are still stripped", line: `foo   \ `, want: "foo "},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := trimTrailingSpace(tc.line); got != tc.want {
+				t.Errorf("trimTrailingSpace(%q) = %q, want %q", tc.line, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGitIgnoreStats(t *testing.T) {
+	g := CompileIgnoreLinesWithOptions(CompileOptions{Cache: 2}, "*.log")
+
+	g.Match("debug.log")
+	g.Match("debug.log")
+	g.Match("other.log")
+
+	stats := g.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("Hits = %d, want 1", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Misses = %d, want 2", stats.Misses)
+	}
+}
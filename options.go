@@ -0,0 +1,14 @@
+package main
+
+// CompileOptions controls how patterns are compiled by
+// parseWithOptions and the GitIgnore ...WithOptions constructors.
+type CompileOptions struct {
+	// CaseInsensitive folds both the compiled pattern and every
+	// path it is matched against to lower case before comparing
+	// them.
+	CaseInsensitive bool
+
+	// Cache sets the maximum number of path -> result entries kept
+	// in a GitIgnore's match cache. Zero disables caching.
+	Cache int
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"strings"
+	"unicode"
+)
+
 const CHAR_SEP = '/'
 const CHAR_SPACE = ' '
 const CHAR_TAB = '\t'
@@ -8,6 +13,9 @@ const CHAR_OPTION = '?'
 const CHAR_CHOICE_START = '['
 const CHAR_CHOICE_END = ']'
 const CHAR_NEGATE = '!'
+const CHAR_CLASS_NEGATE = '^'
+const CHAR_RANGE = '-'
+const CHAR_ESCAPE = '\\'
 
 type Matcher func(Input) (bool, Input)
 
@@ -20,13 +28,15 @@ type Rule struct {
 	IsNegate bool
 }
 
-// Helper function to match a given pattern in input.
-func seq(pattern string, input Input) (bool, Input) {
+// Helper function to match a given pattern in input. When ci is
+// true, both pattern and input runes are folded to lower case
+// before comparison.
+func seq(pattern string, input Input, ci bool) (bool, Input) {
 	rest := input
 
 	for _, next := range pattern {
 		c, eof := rest.current()
-		if eof || next != c {
+		if eof || fold(next, ci) != fold(c, ci) {
 			return false, input
 		}
 		rest.advance()
@@ -35,6 +45,15 @@ func seq(pattern string, input Input) (bool, Input) {
 	return true, rest
 }
 
+// fold lower-cases c when ci is true, and returns it unchanged
+// otherwise.
+func fold(c rune, ci bool) rune {
+	if !ci {
+		return c
+	}
+	return unicode.ToLower(c)
+}
+
 func positiveMatcher(i Input) (bool, Input) {
 	return true, i
 }
@@ -70,8 +89,11 @@ func chain(first Matcher, second Matcher) Matcher {
 }
 
 // tryExactMatcher creates a matcher to match each character
-// in the pattern until the next marker character.
-func tryExactMatcher(pattern Input) (Matcher, Input) {
+// in the pattern until the next marker character. When ci is
+// true, the compiled literal is folded to lower case here, and the
+// returned matcher compares it through seq, which folds both the
+// literal and the input runes.
+func tryExactMatcher(pattern Input, ci bool) (Matcher, Input) {
 	p := []rune{}
 
 	for true {
@@ -80,32 +102,54 @@ func tryExactMatcher(pattern Input) (Matcher, Input) {
 			break
 		}
 
-		p = append(p, c)
+		p = append(p, fold(c, ci))
 		c, eof = pattern.advance()
 
 		if c == CHAR_WILDCARD ||
 			c == CHAR_SEP ||
 			c == CHAR_CHOICE_START ||
-			c == CHAR_OPTION {
+			c == CHAR_OPTION ||
+			c == CHAR_ESCAPE {
 			break
 		}
 	}
 
 	return func(i Input) (bool, Input) {
-		return seq(string(p), i)
+		return seq(string(p), i, ci)
 	}, pattern
 }
 
+// tryEscapeMatcher creates a matcher for the single literal
+// character following a backslash, allowing patterns to escape
+// metacharacters such as *, ?, [ and ], and allowing a leading
+// \# or \! to start a pattern with those characters literally.
+func tryEscapeMatcher(i Input, ci bool) (Matcher, Input) {
+	ok, rest := seq(string(CHAR_ESCAPE), i, false)
+	if !ok {
+		return nil, i
+	}
+
+	c, eof := rest.current()
+	if eof {
+		return nil, i
+	}
+	rest.advance()
+
+	return func(i Input) (bool, Input) {
+		return seq(string(c), i, ci)
+	}, rest
+}
+
 // tryWildcardMatcher creates a matcher to match
 // any character followed by the rest of the pattern string.
 // Matching terminates when it encounters the next slash.
-func tryWildcardMatcher(pattern Input) (Matcher, Input) {
-	ok, rest := seq("*", pattern)
+func tryWildcardMatcher(pattern Input, ci bool) (Matcher, Input) {
+	ok, rest := seq("*", pattern, false)
 	if !ok {
 		return nil, rest
 	}
 
-	suffix, rest := createMatcher(rest)
+	suffix, rest := createMatcher(rest, ci)
 
 	return func(i Input) (bool, Input) {
 		copy := i
@@ -134,7 +178,7 @@ func tryWildcardMatcher(pattern Input) (Matcher, Input) {
 // If input only matches the first slash, matcher will return
 // true but will consume only first character.
 func tryAnySegmentMatcher(i Input) (Matcher, Input) {
-	ok, rest := seq("/*/", i)
+	ok, rest := seq("/*/", i, false)
 
 	if !ok {
 		return nil, i
@@ -165,13 +209,13 @@ func tryAnySegmentMatcher(i Input) (Matcher, Input) {
 // tryManySegmentsMatcher creates a matcher to match many slash
 // separated segments by rest of the pattern.
 // In contrast to tryAnySegmentMatcher, this matcher consumes the slashes.
-func tryManySegmentsMatcher(i Input) (Matcher, Input) {
-	ok, rest := seq("/**/", i)
+func tryManySegmentsMatcher(i Input, ci bool) (Matcher, Input) {
+	ok, rest := seq("/**/", i, false)
 	if !ok {
 		return nil, i
 	}
 
-	suffix, i := createMatcher(rest)
+	suffix, i := createMatcher(rest, ci)
 
 	return func(i Input) (bool, Input) {
 		copy := i
@@ -199,38 +243,164 @@ func tryManySegmentsMatcher(i Input) (Matcher, Input) {
 	}, i
 }
 
-// tryChoiceMatcher creates a matcher to match any character
-// in the specified set.
-func tryChoiceMatcher(i Input) (Matcher, Input) {
-	// TODO: support ranges
+// tryLeadingManySegmentsMatcher recognizes a pattern that begins
+// with `**/`, matching the rest of the pattern at any depth by
+// allowing zero or more leading path segments to be skipped.
+func tryLeadingManySegmentsMatcher(i Input, ci bool) (Matcher, Input) {
+	ok, rest := seq("**/", i, false)
+	if !ok {
+		return nil, i
+	}
+
+	suffix, rest := createMatcher(rest, ci)
+
+	return func(i Input) (bool, Input) {
+		for true {
+			ok, rest := suffix(i)
+			if ok {
+				return ok, rest
+			}
+
+			for {
+				c, eof := i.current()
+				if eof {
+					return false, i
+				}
+				i.advance()
+				if c == CHAR_SEP {
+					break
+				}
+			}
+		}
+
+		panic("unreachable")
+	}, rest
+}
+
+// tryTrailingManySegmentsMatcher recognizes a pattern that ends
+// with `/**`, matching the preceding prefix plus everything beneath
+// it.
+func tryTrailingManySegmentsMatcher(i Input) (Matcher, Input) {
+	ok, rest := seq("/**", i, false)
+	if !ok {
+		return nil, i
+	}
+	if _, eof := rest.current(); !eof {
+		return nil, i
+	}
+
+	return func(i Input) (bool, Input) {
+		c, eof := i.current()
+		if eof {
+			return true, i
+		}
+		if c != CHAR_SEP {
+			return false, i
+		}
+
+		for !eof {
+			c, eof = i.advance()
+		}
+		return true, i
+	}, rest
+}
+
+// choiceRange is a single rune or an inclusive range of runes
+// (e.g. the `a-z` in `[a-z]`) inside a bracket expression.
+type choiceRange struct {
+	lo rune
+	hi rune
+}
+
+func (r choiceRange) contains(c rune) bool {
+	return c >= r.lo && c <= r.hi
+}
+
+// tryChoiceMatcher creates a matcher to match any character in the
+// specified bracket expression, e.g. [abc], [a-z], or the negated
+// forms [!a-z] and [^0-9]. A `]` appearing as the first character
+// of the class (after an optional negation marker) is treated as a
+// literal rather than the closing bracket, matching the bracket
+// expression rules used by git's wildmatch. A backslash escapes the
+// rune that follows it, e.g. [\]\\\-] matches `]`, `\` or `-`. When
+// ci is true, every range endpoint is folded to lower case once
+// here so the returned matcher only has to fold the input it reads.
+func tryChoiceMatcher(i Input, ci bool) (Matcher, Input) {
 	copy := i
 	c, _ := i.current()
 	if c != CHAR_CHOICE_START {
 		return nil, copy
 	}
+	c, eof := i.advance()
+
+	negate := false
+	if !eof && (c == CHAR_NEGATE || c == CHAR_CLASS_NEGATE) {
+		negate = true
+		c, eof = i.advance()
+	}
 
-	choices := make(map[rune]bool)
+	ranges := []choiceRange{}
+	first := true
 
-	for true {
-		i.advance()
-		c, eof := i.current()
+	for {
 		if eof {
 			return nil, copy
 		}
-		if c == CHAR_CHOICE_END {
+		if c == CHAR_CHOICE_END && !first {
 			i.advance()
 			break
 		}
-		choices[c] = true
+		first = false
+
+		if c == CHAR_ESCAPE {
+			c, eof = i.advance()
+			if eof {
+				return nil, copy
+			}
+		}
+		lo, hi := c, c
+
+		c, eof = i.advance()
+		if !eof && c == CHAR_RANGE {
+			la := i
+			next, lEof := la.advance()
+			if !lEof && next != CHAR_CHOICE_END {
+				if next == CHAR_ESCAPE {
+					next, lEof = la.advance()
+					if lEof {
+						return nil, copy
+					}
+				}
+				hi = next
+				i = la
+				c, eof = i.advance()
+			}
+		}
+
+		ranges = append(ranges, choiceRange{lo: fold(lo, ci), hi: fold(hi, ci)})
 	}
 
 	return func(i Input) (bool, Input) {
-		c, _ := i.current()
-		if choices[c] {
-			i.advance()
-			return true, i
+		c, eof := i.current()
+		if eof {
+			return false, i
+		}
+		c = fold(c, ci)
+
+		matched := false
+		for _, r := range ranges {
+			if r.contains(c) {
+				matched = true
+				break
+			}
+		}
+
+		if matched == negate {
+			return false, i
 		}
-		return false, i
+
+		i.advance()
+		return true, i
 	}, i
 }
 
@@ -307,10 +477,10 @@ func tryEmptyMatcher(i Input) (Matcher, Input) {
 	return negativeMatcher, copy
 }
 
-// createMatcher converts an input containing a pattern
-// string to a matcher function that can be used to match the
-// corresponding pattern.
-func createMatcher(i Input) (Matcher, Input) {
+// createMatcher converts an input containing a pattern string to a
+// matcher function that can be used to match the corresponding
+// pattern. When ci is true, the matcher compares case-insensitively.
+func createMatcher(i Input, ci bool) (Matcher, Input) {
 	// default matcher returns true without
 	// consuming any input.
 	p := positiveMatcher
@@ -331,7 +501,10 @@ func createMatcher(i Input) (Matcher, Input) {
 				continue
 			}
 		case CHAR_SEP:
-			matcher, rest = tryManySegmentsMatcher(i)
+			matcher, rest = tryTrailingManySegmentsMatcher(i)
+			if matcher == nil {
+				matcher, rest = tryManySegmentsMatcher(i, ci)
+			}
 			if matcher == nil {
 				matcher, rest = tryAnySegmentMatcher(i)
 			}
@@ -339,13 +512,28 @@ func createMatcher(i Input) (Matcher, Input) {
 				matcher, rest = trySeparatorMatcher(i)
 			}
 		case CHAR_WILDCARD:
-			matcher, rest = tryWildcardMatcher(i)
+			if i.position == 0 {
+				matcher, rest = tryLeadingManySegmentsMatcher(i, ci)
+			}
+			if matcher == nil {
+				matcher, rest = tryWildcardMatcher(i, ci)
+			}
 		case CHAR_CHOICE_START:
-			matcher, rest = tryChoiceMatcher(i)
+			matcher, rest = tryChoiceMatcher(i, ci)
 		case CHAR_OPTION:
 			matcher, rest = tryOptionMatcher(i)
+		case CHAR_ESCAPE:
+			matcher, rest = tryEscapeMatcher(i, ci)
 		default:
-			matcher, rest = tryExactMatcher(i)
+			matcher, rest = tryExactMatcher(i, ci)
+		}
+
+		if matcher == nil {
+			// The pattern is malformed (e.g. a trailing,
+			// un-escaped `\` or an unterminated `[...]`):
+			// give up on it rather than handing chain a
+			// nil matcher to panic on.
+			return negativeMatcher, i
 		}
 
 		p = chain(p, matcher)
@@ -355,11 +543,58 @@ func createMatcher(i Input) (Matcher, Input) {
 	return chain(p, eofMatcher), i
 }
 
+// isAnchored reports whether pattern is anchored to a specific
+// directory, i.e. it contains a `/` anywhere other than as its
+// final character. A trailing slash only marks a directory-only
+// rule and does not anchor the match, so a pattern such as "foo/"
+// is still expected to match "foo" at any depth.
+func isAnchored(pattern string) bool {
+	return strings.ContainsRune(strings.TrimSuffix(pattern, "/"), CHAR_SEP)
+}
+
+// anyDepth wraps a matcher compiled from an unanchored pattern so
+// it matches starting at any path depth, equivalent to an implicit
+// leading **/.
+func anyDepth(m Matcher) Matcher {
+	return func(i Input) (bool, Input) {
+		for true {
+			if ok, rest := m(i); ok {
+				return ok, rest
+			}
+
+			for {
+				c, eof := i.current()
+				if eof {
+					return false, i
+				}
+				i.advance()
+				if c == CHAR_SEP {
+					break
+				}
+			}
+		}
+
+		panic("unreachable")
+	}
+}
+
+// parse compiles a single .gitignore pattern line into a Rule
+// using the default CompileOptions. See parseWithOptions to compile
+// with case-insensitive matching.
 func parse(line string) *Rule {
+	return parseWithOptions(line, CompileOptions{})
+}
+
+// parseWithOptions compiles a single .gitignore pattern line into a
+// Rule, applying opts.CaseInsensitive to the compiled matcher.
+func parseWithOptions(line string, opts CompileOptions) *Rule {
 	i := newInput(line)
 	p, _ := tryEmptyMatcher(i)
 	if p == nil {
-		p, _ = createMatcher(i)
+		p, _ = createMatcher(i, opts.CaseInsensitive)
+		if !isAnchored(line) {
+			p = anyDepth(p)
+		}
 	}
 
 	l, _ := i.last()
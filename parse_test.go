@@ -0,0 +1,130 @@
+package main
+
+import "testing"
+
+func TestTryChoiceMatcher(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		input   string
+		want    bool
+		wantNil bool
+	}{
+		{name: "range hit", pattern: "[a-z]", input: "m", want: true},
+		{name: "range miss", pattern: "[a-z]", input: "M", want: false},
+		{name: "negated range hit", pattern: "[!a-z]", input: "M", want: true},
+		{name: "negated range miss", pattern: "[!a-z]", input: "m", want: false},
+		{name: "caret negation", pattern: "[^0-9]", input: "a", want: true},
+		{name: "literal leading close bracket hit", pattern: "[]abc]", input: "]", want: true},
+		{name: "literal leading close bracket miss", pattern: "[]abc]", input: "d", want: false},
+		{name: "trailing dash is literal", pattern: "[a-]", input: "-", want: true},
+		{name: "empty class", pattern: "[]", wantNil: true},
+		{name: "unterminated class", pattern: "[abc", wantNil: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _ := tryChoiceMatcher(newInput(tc.pattern), false)
+
+			if tc.wantNil {
+				if m != nil {
+					t.Fatalf("tryChoiceMatcher(%q) = non-nil matcher, want nil", tc.pattern)
+				}
+				return
+			}
+
+			if m == nil {
+				t.Fatalf("tryChoiceMatcher(%q) = nil matcher, want non-nil", tc.pattern)
+			}
+
+			got, _ := m(newInput(tc.input))
+			if got != tc.want {
+				t.Errorf("match(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTryChoiceMatcherCaseInsensitive(t *testing.T) {
+	m, _ := tryChoiceMatcher(newInput("[a-z]"), true)
+	if m == nil {
+		t.Fatal("tryChoiceMatcher returned nil matcher")
+	}
+
+	got, _ := m(newInput("M"))
+	if !got {
+		t.Errorf("case-insensitive match(%q) = false, want true", "M")
+	}
+}
+
+func TestParseDoubleStar(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		input   string
+		want    bool
+	}{
+		{name: "leading **/ matches at the root", pattern: "**/foo", input: "foo", want: true},
+		{name: "leading **/ matches at any depth", pattern: "**/foo", input: "a/b/foo", want: true},
+		{name: "leading **/ still requires the suffix to match", pattern: "**/foo", input: "a/foobar", want: false},
+		{name: "trailing /** matches the prefix itself", pattern: "foo/**", input: "foo", want: true},
+		{name: "trailing /** matches anything beneath the prefix", pattern: "foo/**", input: "foo/bar/baz", want: true},
+		{name: "trailing /** does not match an unrelated prefix", pattern: "foo/**", input: "foobar", want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := parse(tc.pattern)
+			if got := r.Matcher(tc.input); got != tc.want {
+				t.Errorf("Matcher(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseUnanchoredMatchesAnyDepth(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		input   string
+		want    bool
+	}{
+		{name: "pattern without a slash matches at the root", pattern: "*.log", input: "debug.log", want: true},
+		{name: "pattern without a slash matches nested paths", pattern: "*.log", input: "a/b/debug.log", want: true},
+		{name: "trailing slash only does not anchor the pattern", pattern: "build/", input: "a/build", want: true},
+		{name: "a slash elsewhere in the pattern anchors it to the root", pattern: "a/build", input: "x/a/build", want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := parse(tc.pattern)
+			if got := r.Matcher(tc.input); got != tc.want {
+				t.Errorf("Matcher(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseEscaping(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		input   string
+		want    bool
+	}{
+		{name: "escaped wildcard is literal", pattern: `foo\*bar`, input: "foo*bar", want: true},
+		{name: "escaped wildcard does not match the wildcarded form", pattern: `foo\*bar`, input: "foobazbar", want: false},
+		{name: "escaped leading hash is literal", pattern: `\#keep`, input: "#keep", want: true},
+		{name: "escaped leading bang is literal", pattern: `\!important`, input: "!important", want: true},
+		{name: "escaped bracket in class", pattern: `[\]a]`, input: "]", want: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := parse(tc.pattern)
+			if got := r.Matcher(tc.input); got != tc.want {
+				t.Errorf("Matcher(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import "strings"
+
+// ChildMayMatch reports whether any descendant of path could still
+// match r's pattern, letting a directory walker prune a subtree
+// once it knows no descendant needs to be checked individually.
+// The approach mirrors restic's filter.ChildMatch optimization: run
+// the compiled matcher first, and if it fails, reason about the raw
+// pattern to decide whether extending path with more `/segment`
+// components could still reach a match.
+func (r *Rule) ChildMayMatch(path string) bool {
+	if r.Matcher(path) {
+		return true
+	}
+	return childMayMatch(r.Pattern, path)
+}
+
+// childMayMatch implements the descent heuristic for a single raw
+// pattern:
+//   - a pattern containing `**`, or one that isn't rooted with a
+//     leading `/`, can match at any depth, so descent is always
+//     worth it
+//   - a rooted, literal-prefix pattern has a prefix up to its
+//     first wildcard; descent is only worth it while path is still
+//     a possible prefix match of that literal prefix
+func childMayMatch(pattern string, path string) bool {
+	p := strings.TrimSuffix(pattern, "/")
+
+	if strings.Contains(p, "**") {
+		return true
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		return true
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	prefix := p
+	if end := strings.IndexAny(p, "*?["); end >= 0 {
+		prefix = p[:end]
+	}
+
+	if len(path) >= len(prefix) {
+		return strings.HasPrefix(path, prefix)
+	}
+	return strings.HasPrefix(prefix, path)
+}
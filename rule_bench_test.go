@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// treeNode is one entry in a synthetic directory tree used to
+// benchmark ChildMayMatch-based pruning over a realistically large
+// walk.
+type treeNode struct {
+	name     string
+	isDir    bool
+	children []*treeNode
+}
+
+// buildTree synthesizes a directory tree width children wide and
+// depth levels deep, rooted under several top-level directories
+// named "src0".."srcN" plus one sibling named "vendor". None of the
+// "src*" subtrees share any path prefix with a `/vendor` rule, so
+// their entire, deeply nested contents are the kind of irrelevant
+// subtree ChildMayMatch lets a walker skip without visiting a
+// single descendant; "vendor" itself shares the rule's prefix and
+// so is walked in full either way, giving the benchmark a
+// realistic mix of prunable and non-prunable subtrees.
+func buildTree(width, depth int) *treeNode {
+	root := &treeNode{name: "root", isDir: true}
+
+	var add func(n *treeNode, d int)
+	add = func(n *treeNode, d int) {
+		if d == 0 {
+			return
+		}
+		for i := 0; i < width; i++ {
+			if i == width-1 {
+				n.children = append(n.children, &treeNode{name: fmt.Sprintf("file%d.go", i), isDir: false})
+				continue
+			}
+			child := &treeNode{name: fmt.Sprintf("dir%d", i), isDir: true}
+			n.children = append(n.children, child)
+			add(child, d-1)
+		}
+	}
+
+	for i := 0; i < width; i++ {
+		src := &treeNode{name: fmt.Sprintf("src%d", i), isDir: true}
+		add(src, depth)
+		root.children = append(root.children, src)
+	}
+
+	vendor := &treeNode{name: "vendor", isDir: true}
+	add(vendor, depth)
+	root.children = append(root.children, vendor)
+
+	return root
+}
+
+// walkUnpruned visits every node in the tree, matching each one in
+// turn without ever consulting childMayMatch - the baseline a
+// directory walker falls back to without the fast path, equivalent
+// to restic's un-pruned BenchmarkFilterPatterns shape.
+func walkUnpruned(g *GitIgnore, n *treeNode, prefix string) int {
+	path := prefix + n.name
+	g.MatchIsDir(path, n.isDir)
+
+	visited := 1
+	for _, c := range n.children {
+		visited += walkUnpruned(g, c, path+"/")
+	}
+	return visited
+}
+
+// walkPruned mirrors how a real directory walker would use
+// MatchIsDir's second return value: once childMayMatch is false for
+// a directory, none of g's rules could ever match or un-match
+// anything beneath it, so its entire subtree is skipped instead of
+// being visited node by node.
+func walkPruned(g *GitIgnore, n *treeNode, prefix string) int {
+	path := prefix + n.name
+	_, childMayMatch := g.MatchIsDir(path, n.isDir)
+
+	visited := 1
+	if !childMayMatch {
+		return visited
+	}
+	for _, c := range n.children {
+		visited += walkPruned(g, c, path+"/")
+	}
+	return visited
+}
+
+// BenchmarkGitIgnoreWalk walks a synthetic tree of some thirty
+// thousand paths - most of them under top-level directories a `/vendor` rule
+// can never reach - with and without ChildMayMatch-based pruning,
+// to show the fast path's win on workloads of that size.
+func BenchmarkGitIgnoreWalk(b *testing.B) {
+	tree := buildTree(6, 5)
+	rules := []string{"/vendor"}
+
+	cases := []struct {
+		name string
+		walk func(g *GitIgnore, n *treeNode, prefix string) int
+	}{
+		{name: "no pruning", walk: walkUnpruned},
+		{name: "ChildMayMatch pruning", walk: walkPruned},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			g := CompileIgnoreLines(rules...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, top := range tree.children {
+					tc.walk(g, top, "")
+				}
+			}
+		})
+	}
+}